@@ -0,0 +1,183 @@
+// Package format renders texcheck.Diagnostic slices as machine-readable
+// output for CI pipelines and code-review tooling: plain JSON for
+// general scripting, and SARIF 2.1.0 for GitHub Code Scanning.
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/timjs/tex-check-go/texcheck"
+)
+
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+type jsonRelated struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Message string `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	File     string        `json:"file"`
+	Line     int           `json:"line"`
+	Col      int           `json:"col"`
+	Severity string        `json:"severity"`
+	Code     string        `json:"code"`
+	Message  string        `json:"message"`
+	Related  []jsonRelated `json:"related,omitempty"`
+}
+
+// JSON writes one object per Diagnostic to w, in document order.
+func JSON(w io.Writer, diagnostics []texcheck.Diagnostic) error {
+	out := make([]jsonDiagnostic, len(diagnostics))
+	for i, d := range diagnostics {
+		out[i] = toJSONDiagnostic(d)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func toJSONDiagnostic(d texcheck.Diagnostic) jsonDiagnostic {
+	related := make([]jsonRelated, len(d.Related))
+	for i, r := range d.Related {
+		related[i] = jsonRelated{File: r.Pos.File, Line: r.Pos.Line, Col: r.Pos.Col, Message: r.Message}
+	}
+	return jsonDiagnostic{
+		File:     d.Pos.File,
+		Line:     d.Pos.Line,
+		Col:      d.Pos.Col,
+		Severity: d.Severity.String(),
+		Code:     d.Code,
+		Message:  d.Message,
+		Related:  related,
+	}
+}
+
+// SARIF types, following the 2.1.0 schema just deep enough to
+// populate the fields GitHub Code Scanning renders.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           string                 `json:"ruleId"`
+	Level            string                 `json:"level"`
+	Message          sarifMessage           `json:"message"`
+	Locations        []sarifLocation        `json:"locations"`
+	RelatedLocations []sarifRelatedLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifRelatedLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          sarifMessage          `json:"message"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// SARIF writes diagnostics as a single SARIF 2.1.0 log with one run
+// for toolName.
+func SARIF(w io.Writer, toolName string, diagnostics []texcheck.Diagnostic) error {
+	ruleSet := map[string]bool{}
+	var rules []sarifRule
+	results := make([]sarifResult, len(diagnostics))
+	for i, d := range diagnostics {
+		if !ruleSet[d.Code] {
+			ruleSet[d.Code] = true
+			rules = append(rules, sarifRule{ID: d.Code})
+		}
+
+		related := make([]sarifRelatedLocation, len(d.Related))
+		for j, r := range d.Related {
+			related[j] = sarifRelatedLocation{
+				PhysicalLocation: toSARIFLocation(r.Pos),
+				Message:          sarifMessage{Text: r.Message},
+			}
+		}
+
+		results[i] = sarifResult{
+			RuleID:           d.Code,
+			Level:            sarifLevel(d.Severity),
+			Message:          sarifMessage{Text: d.Message},
+			Locations:        []sarifLocation{{PhysicalLocation: toSARIFLocation(d.Pos)}},
+			RelatedLocations: related,
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: "https://github.com/timjs/tex-check-go",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func toSARIFLocation(pos texcheck.Position) sarifPhysicalLocation {
+	return sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: pos.File},
+		Region:           sarifRegion{StartLine: pos.Line, StartColumn: pos.Col},
+	}
+}
+
+func sarifLevel(s texcheck.Severity) string {
+	switch s {
+	case texcheck.Warning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
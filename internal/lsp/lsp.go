@@ -0,0 +1,267 @@
+// Package lsp speaks a minimal subset of the Language Server Protocol
+// over stdio so editors can get live balance diagnostics for open
+// (La)TeX/ConTeXt documents. It implements just enough of the
+// protocol (initialize, didOpen, didChange, publishDiagnostics,
+// shutdown, exit) to drive tex-check-go's Checker from a running
+// editor session; it is not a general-purpose LSP library.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timjs/tex-check-go/texcheck"
+)
+
+// Serve reads JSON-RPC requests/notifications from r and writes
+// responses/notifications to w until the client sends "exit".
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{in: bufio.NewReader(r), out: w, docs: map[string]string{}}
+	return s.run()
+}
+
+type server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]string // uri -> last known content
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (s *server) run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			continue
+		}
+
+		switch req.Method {
+		case "initialize":
+			s.reply(req.ID, initializeResult{
+				Capabilities: serverCapabilities{
+					TextDocumentSync: textDocumentSyncOptions{OpenClose: true, Change: 1}, // 1 = Full
+				},
+			})
+		case "initialized":
+			// nothing to do
+		case "textDocument/didOpen":
+			var p didOpenParams
+			if json.Unmarshal(req.Params, &p) == nil {
+				s.check(p.TextDocument.URI, p.TextDocument.Text)
+			}
+		case "textDocument/didChange":
+			var p didChangeParams
+			if json.Unmarshal(req.Params, &p) == nil && len(p.ContentChanges) > 0 {
+				// Full sync: the last change carries the whole document.
+				text := p.ContentChanges[len(p.ContentChanges)-1].Text
+				s.check(p.TextDocument.URI, text)
+			}
+		case "textDocument/didClose":
+			var p didCloseParams
+			if json.Unmarshal(req.Params, &p) == nil {
+				delete(s.docs, p.TextDocument.URI)
+			}
+		case "shutdown":
+			s.reply(req.ID, nil)
+		case "exit":
+			return nil
+		}
+	}
+}
+
+// check re-runs the balance checker over text and publishes the
+// resulting diagnostics for uri.
+func (s *server) check(uri, text string) {
+	s.docs[uri] = text
+	checker := &texcheck.Checker{Filename: uriToFilename(uri)}
+	diagnostics, _ := checker.Check(strings.NewReader(text))
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: toLSPDiagnostics(uri, diagnostics),
+	})
+}
+
+func toLSPDiagnostics(uri string, diagnostics []texcheck.Diagnostic) []diagnostic {
+	out := make([]diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		related := make([]relatedInformation, 0, len(d.Related))
+		for _, r := range d.Related {
+			related = append(related, relatedInformation{
+				Location: location{URI: uri, Range: toRange(r.Pos)},
+				Message:  r.Message,
+			})
+		}
+		out = append(out, diagnostic{
+			Range:              toRange(d.Pos),
+			Severity:           toLSPSeverity(d.Severity),
+			Code:               d.Code,
+			Source:             "tex-check-go",
+			Message:            d.Message,
+			RelatedInformation: related,
+		})
+	}
+	return out
+}
+
+func toRange(pos texcheck.Position) lspRange {
+	p := position{Line: pos.Line - 1, Character: pos.Col - 1}
+	return lspRange{Start: p, End: p}
+}
+
+func toLSPSeverity(s texcheck.Severity) int {
+	switch s {
+	case texcheck.Warning:
+		return 2 // Warning
+	default:
+		return 1 // Error
+	}
+}
+
+func uriToFilename(uri string) string {
+	return strings.TrimPrefix(uri, "file://")
+}
+
+func (s *server) reply(id json.RawMessage, result interface{}) {
+	s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      json.RawMessage(id),
+		"result":  result,
+	})
+}
+
+func (s *server) notify(method string, params interface{}) {
+	s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+}
+
+func (s *server) write(v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length:") {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+			if err != nil {
+				return nil, err
+			}
+			length = n
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("lsp: message with no Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Wire types, named after their lsp spec counterparts.
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync textDocumentSyncOptions `json:"textDocumentSync"`
+}
+
+type textDocumentSyncOptions struct {
+	OpenClose bool `json:"openClose"`
+	Change    int  `json:"change"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type contentChange struct {
+	Text string `json:"text"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange        `json:"contentChanges"`
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type relatedInformation struct {
+	Location location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+type diagnostic struct {
+	Range              lspRange             `json:"range"`
+	Severity           int                  `json:"severity"`
+	Code               string               `json:"code,omitempty"`
+	Source             string               `json:"source,omitempty"`
+	Message            string               `json:"message"`
+	RelatedInformation []relatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
@@ -0,0 +1,114 @@
+// Command tex-check-go checks (La)TeX and ConTeXt documents for
+// unbalanced braces, brackets, math delimiters and start/stop
+// environments.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/timjs/tex-check-go/internal/format"
+	"github.com/timjs/tex-check-go/internal/lsp"
+	"github.com/timjs/tex-check-go/texcheck"
+)
+
+// Exit codes: 0 clean, 1 diagnostics reported, 2 I/O error.
+const (
+	exitClean       = 0
+	exitDiagnostics = 1
+	exitIOError     = 2
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+		return
+	}
+
+	configPath := flag.String("config", "", "path to a JSON rules file extending the default verbatim and paired-macro rules (math delimiters are built in and not configurable)")
+	outputFormat := flag.String("format", "text", "output format: text, json or sarif")
+	recoverFlag := flag.String("recover", "off", "error recovery strategy: off, panic or synchronize")
+	maxErrors := flag.Int("max-errors", 0, "stop reporting after this many diagnostics per file (0 = unlimited)")
+	flag.Parse()
+
+	recoverMode, err := texcheck.ParseRecoverMode(*recoverFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitIOError)
+	}
+
+	rules := texcheck.DefaultRules
+	if *configPath != "" {
+		f, err := os.Open(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+		rules, err = texcheck.LoadRules(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+	}
+
+	var allDiagnostics []texcheck.Diagnostic
+	ioError := false
+
+	for _, a := range flag.Args() {
+		f, err := os.Open(a)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ioError = true
+			continue
+		}
+
+		if *outputFormat == "text" {
+			fmt.Printf(">> %s...\n", a)
+		}
+		checker := &texcheck.Checker{Filename: a, Rules: rules, Recover: recoverMode, MaxErrors: *maxErrors}
+		diagnostics, err := checker.Check(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ioError = true
+			continue
+		}
+
+		if *outputFormat == "text" {
+			for _, d := range diagnostics {
+				fmt.Println(d)
+			}
+		}
+		allDiagnostics = append(allDiagnostics, diagnostics...)
+	}
+
+	switch *outputFormat {
+	case "text":
+		// already printed above
+	case "json":
+		if err := format.JSON(os.Stdout, allDiagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+	case "sarif":
+		if err := format.SARIF(os.Stdout, "tex-check-go", allDiagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --format %q: want text, json or sarif\n", *outputFormat)
+		os.Exit(exitIOError)
+	}
+
+	if ioError {
+		os.Exit(exitIOError)
+	}
+	if len(allDiagnostics) > 0 {
+		os.Exit(exitDiagnostics)
+	}
+}
@@ -0,0 +1,59 @@
+package texcheck
+
+import "fmt"
+
+// RecoverMode controls what the Checker does when it sees a closer
+// that doesn't match the top of the stack.
+type RecoverMode int
+
+const (
+	// RecoverOff reports the mismatch and leaves the stack untouched,
+	// which is how the checker has always behaved: a single stray
+	// closer can cascade into spurious errors for the rest of the
+	// document.
+	RecoverOff RecoverMode = iota
+	// RecoverPanic reports the mismatch and stops checking the
+	// document, like a parser that gives up after its first error
+	// instead of guessing how to continue.
+	RecoverPanic
+	// RecoverSynchronize searches a bounded number of frames down the
+	// stack for a matching opener. If one is found, every frame above
+	// it is reported as auto-closed and discarded, and checking
+	// resumes from the matching opener. If none is found within the
+	// bound, the closer is treated as spurious and checking continues
+	// without touching the stack.
+	RecoverSynchronize
+)
+
+func (m RecoverMode) String() string {
+	switch m {
+	case RecoverOff:
+		return "off"
+	case RecoverPanic:
+		return "panic"
+	case RecoverSynchronize:
+		return "synchronize"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRecoverMode parses the --recover flag values: "off", "panic"
+// or "synchronize".
+func ParseRecoverMode(s string) (RecoverMode, error) {
+	switch s {
+	case "off":
+		return RecoverOff, nil
+	case "panic":
+		return RecoverPanic, nil
+	case "synchronize":
+		return RecoverSynchronize, nil
+	default:
+		return RecoverOff, fmt.Errorf("texcheck: unknown recover mode %q: want off, panic or synchronize", s)
+	}
+}
+
+// maxRecoveryDepth bounds how far RecoverSynchronize searches down the
+// stack for a matching opener, so a deeply nested document can't make
+// recovery itself slow.
+const maxRecoveryDepth = 32
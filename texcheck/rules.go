@@ -0,0 +1,101 @@
+package texcheck
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// MacroPair declares a custom paired macro, such as ConTeXt's
+// \bigl/\bigr, that should push and pop like the built-in \left/
+// \right.
+type MacroPair struct {
+	Opener string `json:"opener"`
+	Closer string `json:"closer"`
+}
+
+func (m MacroPair) opening() string { return m.Opener }
+func (m MacroPair) closing() string { return m.Closer }
+
+// Rules configures which environments and macros the Checker treats
+// specially, so that users of ConTeXt, LaTeX listings packages and
+// other dialects can extend the tool without recompiling it.
+type Rules struct {
+	// VerbatimEnvironments are \start<name> names (DefaultRules has
+	// "typing") whose body is taken verbatim, up to the matching
+	// \stop<name>.
+	VerbatimEnvironments []string `json:"verbatimEnvironments"`
+	// VerbatimBeginEnd are \begin{name}...\end{name} names whose body
+	// is taken verbatim, e.g. "lstlisting" or "minted".
+	VerbatimBeginEnd []string `json:"verbatimBeginEnd"`
+	// VerbatimDelimiters are single-byte symbols (DefaultRules has
+	// "@") that toggle verbatim mode on sight.
+	VerbatimDelimiters []string `json:"verbatimDelimiters"`
+	// DelimitedVerbatimMacros are macro names (DefaultRules has
+	// "\type" and "\verb") that read a one-byte delimiter and stay
+	// verbatim until that same delimiter recurs, like LaTeX's \verb.
+	DelimitedVerbatimMacros []string `json:"delimitedVerbatimMacros"`
+	// PairedMacros are additional literal macro pairs that push/pop
+	// like \left/\right.
+	PairedMacros []MacroPair `json:"pairedMacros"`
+}
+
+// DefaultRules covers plain LaTeX and ConTeXt out of the box.
+var DefaultRules = Rules{
+	VerbatimEnvironments:    []string{"typing"},
+	VerbatimBeginEnd:        []string{"verbatim", "Verbatim", "lstlisting", "minted"},
+	VerbatimDelimiters:      []string{"@"},
+	DelimitedVerbatimMacros: []string{"\\type", "\\verb"},
+}
+
+func (r Rules) isZero() bool {
+	return len(r.VerbatimEnvironments) == 0 &&
+		len(r.VerbatimBeginEnd) == 0 &&
+		len(r.VerbatimDelimiters) == 0 &&
+		len(r.DelimitedVerbatimMacros) == 0 &&
+		len(r.PairedMacros) == 0
+}
+
+// LoadRules reads a JSON rules document from r and merges it onto
+// DefaultRules: callers only need to list the environments and
+// macros they want to add.
+func LoadRules(r io.Reader) (Rules, error) {
+	var extra Rules
+	if err := json.NewDecoder(r).Decode(&extra); err != nil {
+		return Rules{}, err
+	}
+
+	rules := DefaultRules
+	rules.VerbatimEnvironments = append(append([]string{}, rules.VerbatimEnvironments...), extra.VerbatimEnvironments...)
+	rules.VerbatimBeginEnd = append(append([]string{}, rules.VerbatimBeginEnd...), extra.VerbatimBeginEnd...)
+	rules.VerbatimDelimiters = append(append([]string{}, rules.VerbatimDelimiters...), extra.VerbatimDelimiters...)
+	rules.DelimitedVerbatimMacros = append(append([]string{}, rules.DelimitedVerbatimMacros...), extra.DelimitedVerbatimMacros...)
+	rules.PairedMacros = append(append([]MacroPair{}, rules.PairedMacros...), extra.PairedMacros...)
+	return rules, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findPairedOpener(pairs []MacroPair, token string) (MacroPair, bool) {
+	for _, p := range pairs {
+		if p.Opener == token {
+			return p, true
+		}
+	}
+	return MacroPair{}, false
+}
+
+func findPairedCloser(pairs []MacroPair, token string) (MacroPair, bool) {
+	for _, p := range pairs {
+		if p.Closer == token {
+			return p, true
+		}
+	}
+	return MacroPair{}, false
+}
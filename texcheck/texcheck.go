@@ -0,0 +1,629 @@
+// Package texcheck checks (La)TeX and ConTeXt sources for balanced
+// braces, brackets, math delimiters and start/stop environments.
+//
+// It is built the way go/scanner is built: a scanner produces tokens,
+// a checker walks them keeping a stack of open symbols, and an
+// ErrorHandler is notified of each Diagnostic as it is found. Callers
+// that just want the full list can use Check, which collects every
+// Diagnostic and returns them once scanning is done.
+package texcheck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity classifies a Diagnostic.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Position describes a single point in a source file, mirroring
+// go/token.Position: Line and Col are both 1-based, Offset is the
+// 0-based byte offset from the start of the file.
+type Position struct {
+	File   string
+	Line   int
+	Col    int
+	Offset int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Related attaches extra context to a Diagnostic, such as the
+// location of the opener a mismatched closer failed to match.
+type Related struct {
+	Pos     Position
+	Message string
+}
+
+// Diagnostic reports a single balance problem found while checking a
+// document.
+type Diagnostic struct {
+	Pos      Position
+	End      Position
+	Severity Severity
+	Code     string
+	Message  string
+	Related  []Related
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+	for _, r := range d.Related {
+		s += fmt.Sprintf("\n\t%s: %s", r.Pos, r.Message)
+	}
+	return s
+}
+
+// ErrorHandler is called once for every Diagnostic as it is produced,
+// in document order.
+type ErrorHandler func(Diagnostic)
+
+// Checker checks a single document for balance errors.
+type Checker struct {
+	// Filename is recorded on every Position; it need not refer to a
+	// real file and may be left empty.
+	Filename string
+	// Handler, if set, is called for every Diagnostic as it is found,
+	// in addition to it being collected into Check's return value.
+	Handler ErrorHandler
+	// Rules controls which environments and macros are treated as
+	// verbatim or as paired delimiters. The zero value means
+	// DefaultRules.
+	Rules Rules
+	// Recover controls what happens when a closer doesn't match the
+	// top of the stack. The zero value is RecoverOff.
+	Recover RecoverMode
+	// MaxErrors caps how many diagnostics are reported; 0 means no
+	// cap. It does not stop the checker from tracking the stack, only
+	// from reporting once the cap is hit.
+	MaxErrors int
+}
+
+// Check scans r and reports every balance Diagnostic found. Use a
+// Checker directly when the caller wants to supply a Filename or
+// stream Diagnostics through a Handler.
+func Check(r io.Reader) ([]Diagnostic, error) {
+	return new(Checker).Check(r)
+}
+
+// Check scans r and returns every balance Diagnostic found, in
+// document order.
+func (c *Checker) Check(r io.Reader) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	report := func(d Diagnostic) {
+		diagnostics = append(diagnostics, d)
+		if c.Handler != nil {
+			c.Handler(d)
+		}
+	}
+
+	rules := c.Rules
+	if rules.isZero() {
+		rules = DefaultRules
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(splitter)
+	state := &State{
+		line:      1,
+		col:       1,
+		filename:  c.Filename,
+		rules:     rules,
+		recover:   c.Recover,
+		maxErrors: c.MaxErrors,
+		report:    report,
+	}
+	balanced(scanner, state)
+
+	if err := scanner.Err(); err != nil {
+		return diagnostics, err
+	}
+	return diagnostics, nil
+}
+
+type (
+	Symbol interface {
+		opening() string
+		closing() string
+	}
+	Brace        struct{}
+	Bracket      struct{}
+	Paren        struct{}
+	Chevron      struct{}
+	Dollar       struct{}
+	DoubleDollar struct{}
+	ParenMath    struct{}
+	BracketMath  struct{}
+	Delimiter    struct{}
+	Other        byte
+	StartStop    string
+	BeginEnd     string
+	// BraceScope marks a pair of braces that switches Mode for its
+	// argument, such as \ensuremath{...} or \text{...}; prevMode is
+	// restored when the closing brace is popped.
+	BraceScope struct{ prevMode Mode }
+)
+
+func (_ Brace) opening() string        { return "{" }
+func (_ Bracket) opening() string      { return "[" }
+func (_ Paren) opening() string        { return "(" }
+func (_ Chevron) opening() string      { return "<" }
+func (_ Dollar) opening() string       { return "$" }
+func (_ DoubleDollar) opening() string { return "$$" }
+func (_ ParenMath) opening() string    { return "\\(" }
+func (_ BracketMath) opening() string  { return "\\[" }
+func (_ Delimiter) opening() string    { return "\\left" }
+func (b Other) opening() string        { return string(b) }
+func (s StartStop) opening() string    { return "\\start" + string(s) }
+func (s BeginEnd) opening() string     { return "\\begin{" + string(s) + "}" }
+func (_ BraceScope) opening() string   { return "{" }
+
+func (_ Brace) closing() string        { return "}" }
+func (_ Bracket) closing() string      { return "]" }
+func (_ Paren) closing() string        { return ")" }
+func (_ Chevron) closing() string      { return ">" }
+func (_ Dollar) closing() string       { return "$" }
+func (_ DoubleDollar) closing() string { return "$$" }
+func (_ ParenMath) closing() string    { return "\\)" }
+func (_ BracketMath) closing() string  { return "\\]" }
+func (_ Delimiter) closing() string    { return "\\right" }
+func (b Other) closing() string        { return string(b) }
+func (s StartStop) closing() string    { return "\\stop" + string(s) }
+func (s BeginEnd) closing() string     { return "\\end{" + string(s) + "}" }
+func (_ BraceScope) closing() string   { return "}" }
+
+type (
+	Mode  uint
+	Line  uint
+	Stack []LocatedSymbol
+	State struct {
+		mode   Mode
+		line   Line
+		col    int
+		offset int
+		// tokenOffset is the byte offset of the start of the token
+		// most recently returned by scan, so pos() reports the same
+		// point that Line and Col describe instead of its end.
+		tokenOffset int
+		// lineOffset is the byte offset of the first byte of the line
+		// the next token starts on, so that col = offset - lineOffset + 1.
+		lineOffset int
+		stack      Stack
+		filename   string
+		rules      Rules
+		// verbatim accumulates the raw text seen since the start of
+		// the current verbatim body, so its suffix can be compared
+		// against the expected (possibly multi-token) closer.
+		verbatim  string
+		recover   RecoverMode
+		maxErrors int
+		errors    int
+		// stopped is set by RecoverPanic once the first error is
+		// reported, so balanced can stop scanning early.
+		stopped bool
+		report  func(Diagnostic)
+	}
+	LocatedSymbol struct {
+		symbol Symbol
+		pos    Position
+	}
+)
+
+const (
+	NORMAL Mode = iota
+	MATH
+	VERBATIM
+)
+
+func isNewLine(b byte) bool { return b == '\n' || b == '\r' }
+func isSpace(b byte) bool   { return b == ' ' || b == '\t' || b == '\v' || b == '\f' }
+func isLetter(b byte) bool  { return 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' }
+func isDigit(b byte) bool   { return '0' <= b && b <= '9' }
+func isEscape(b byte) bool  { return b == '\\' }
+func isComment(b byte) bool { return b == '%' }
+func isGrouping(b byte) bool {
+	return b == '{' || b == '}' || b == '[' || b == ']' || b == '(' || b == ')' || b == '<' || b == '>' || b == '$' || b == '@'
+}
+func isMathParenOrBracket(b byte) bool { return b == '(' || b == ')' || b == '[' || b == ']' }
+
+func consume(n int, data []byte) (advance int, token []byte, err error) {
+	advance, token, err = n, data[:n], nil
+	return
+}
+
+// consumeTill consumes up to the first byte matching test. If none is
+// found and end is set, the remaining data is the final token instead
+// of asking bufio for more (there is no more).
+func consumeTill(test func(byte) bool, data []byte, end bool) (advance int, token []byte, err error) {
+	for i, b := range data {
+		if test(b) {
+			return i, data[:i], nil
+		}
+	}
+	if end {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// consumeWhile consumes up to the first byte not matching test. If
+// every byte matches and end is set, the remaining data is the final
+// token instead of asking bufio for more (there is no more).
+func consumeWhile(test func(byte) bool, data []byte, end bool) (advance int, token []byte, err error) {
+	for i, b := range data {
+		if !test(b) {
+			return i, data[:i], nil
+		}
+	}
+	if end {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func symbolise(b byte) Symbol {
+	switch b {
+	case '{', '}':
+		return Brace{}
+	case '[', ']':
+		return Bracket{}
+	case '(', ')':
+		return Paren{}
+	case '<', '>':
+		return Chevron{}
+	default:
+		return Other(b)
+	}
+}
+
+func splitter(data []byte, end bool) (advance int, token []byte, err error) {
+	if end && len(data) == 0 {
+		return 0, nil, nil
+	}
+	switch b := data[0]; {
+	case isNewLine(b):
+		advance, token, err = consume(1, data)
+	case isSpace(b):
+		advance, token, err = consumeWhile(isSpace, data, end)
+	case isLetter(b):
+		advance, token, err = consumeWhile(isLetter, data, end)
+	case isDigit(b):
+		advance, token, err = consumeWhile(isDigit, data, end)
+	case isEscape(b):
+		switch {
+		case len(data) < 2 && !end:
+			return 0, nil, nil // request more data to see the escaped byte
+		case len(data) >= 2 && isMathParenOrBracket(data[1]):
+			// \(, \), \[, \] are their own tokens so the checker can
+			// tell them apart from a lone brace or paren.
+			advance, token, err = consume(2, data)
+		default:
+			advance, token, err = consumeWhile(isLetter, data[1:], end)
+			if advance == 0 && token == nil {
+				// consumeWhile wants more data to see where the
+				// macro name ends; asking for the lone '\' now would
+				// mis-tokenize a name straddling a fill boundary.
+				return 0, nil, nil
+			}
+			token = append([]byte{'\\'}, token...)
+			advance++
+		}
+	case isComment(b):
+		advance, token, err = consumeTill(isNewLine, data, end)
+	case b == '$':
+		if len(data) < 2 && !end {
+			return 0, nil, nil // might be the start of "$$"
+		}
+		if len(data) >= 2 && data[1] == '$' {
+			advance, token, err = consume(2, data)
+		} else {
+			advance, token, err = consume(1, data)
+		}
+	default:
+		advance, token, err = consume(1, data)
+	}
+	return
+}
+
+// scan fetches the next token, records its start in state's
+// line/col/tokenOffset and advances offset to just past it, so that
+// state.pos() reports the position of the token that was just
+// returned. A newline token stays on its own line; the line count
+// moves on with the token after it.
+func (state *State) scan(scanner *bufio.Scanner) (token []byte, ok bool) {
+	if ok = scanner.Scan(); !ok {
+		return nil, false
+	}
+	token = scanner.Bytes()
+	if state.lineOffset > state.tokenOffset {
+		// the previous token was a newline
+		state.line++
+	}
+	state.col = state.offset - state.lineOffset + 1
+	state.tokenOffset = state.offset
+	state.offset += len(token)
+	if len(token) > 0 && isNewLine(token[0]) {
+		state.lineOffset = state.offset
+	}
+	return token, true
+}
+
+func balanced(scanner *bufio.Scanner, state *State) {
+	for {
+		token, ok := state.scan(scanner)
+		if !ok {
+			break
+		}
+		switch state.mode {
+		case NORMAL, MATH:
+			switch token[0] {
+			case '\n', '\r':
+				// positions are tracked by scan
+			case '\\':
+				switch {
+				case contains(state.rules.DelimitedVerbatimMacros, string(token)):
+					delim, _ := state.scan(scanner) // delimiter
+					state.push(symbolise(delim[0]))
+					state.mode = VERBATIM
+					state.verbatim = ""
+				case bytes.HasPrefix(token, []byte("\\start")):
+					name := string(bytes.TrimPrefix(token, []byte("\\start")))
+					state.push(StartStop(name))
+					if contains(state.rules.VerbatimEnvironments, name) {
+						state.mode = VERBATIM
+						state.verbatim = ""
+					}
+				case bytes.HasPrefix(token, []byte("\\stop")):
+					name := bytes.TrimPrefix(token, []byte("\\stop"))
+					state.pop(StartStop(name))
+				case bytes.Equal(token, []byte("\\begin")):
+					state.scan(scanner) // '{'
+					name, _ := state.scan(scanner)
+					state.push(BeginEnd(name))
+					state.scan(scanner) // '}'
+					if contains(state.rules.VerbatimBeginEnd, string(name)) {
+						state.mode = VERBATIM
+						state.verbatim = ""
+					}
+				case bytes.Equal(token, []byte("\\end")):
+					state.scan(scanner) // '{'
+					name, _ := state.scan(scanner)
+					state.pop(BeginEnd(name))
+					state.scan(scanner) // '}'
+				case bytes.Equal(token, []byte("\\left")):
+					state.scan(scanner) // delimiter
+					state.push(Delimiter{})
+				case bytes.Equal(token, []byte("\\right")):
+					state.scan(scanner) // delimiter
+					state.pop(Delimiter{})
+				case bytes.Equal(token, []byte("\\(")):
+					state.push(ParenMath{})
+					state.mode = MATH
+				case bytes.Equal(token, []byte("\\)")):
+					state.pop(ParenMath{})
+					state.mode = NORMAL
+				case bytes.Equal(token, []byte("\\[")):
+					state.push(BracketMath{})
+					state.mode = MATH
+				case bytes.Equal(token, []byte("\\]")):
+					state.pop(BracketMath{})
+					state.mode = NORMAL
+				case bytes.Equal(token, []byte("\\ensuremath")):
+					if next, ok := state.scan(scanner); ok && len(next) > 0 && next[0] == '{' {
+						state.push(BraceScope{prevMode: state.mode})
+						state.mode = MATH
+					}
+				case bytes.Equal(token, []byte("\\text")) && state.mode == MATH:
+					if next, ok := state.scan(scanner); ok && len(next) > 0 && next[0] == '{' {
+						state.push(BraceScope{prevMode: state.mode})
+						state.mode = NORMAL
+					}
+				default:
+					if pair, ok := findPairedOpener(state.rules.PairedMacros, string(token)); ok {
+						state.push(pair)
+					} else if pair, ok := findPairedCloser(state.rules.PairedMacros, string(token)); ok {
+						state.pop(pair)
+					}
+				}
+			case '{':
+				state.push(Brace{})
+			case '}':
+				if top, ok := state.peakOrNil(); ok {
+					if scope, ok := top.symbol.(BraceScope); ok {
+						state.stack = state.stack[:len(state.stack)-1]
+						state.mode = scope.prevMode
+						break
+					}
+				}
+				state.pop(Brace{})
+			case '[':
+				state.push(Bracket{})
+			case ']':
+				state.pop(Bracket{})
+			case '(':
+				state.push(Paren{})
+			case ')':
+				state.pop(Paren{})
+			case '$':
+				if len(token) == 2 { // "$$", display math
+					switch state.mode {
+					case MATH:
+						state.pop(DoubleDollar{})
+						state.mode = NORMAL
+					case NORMAL:
+						state.push(DoubleDollar{})
+						state.mode = MATH
+					}
+					break
+				}
+				switch state.mode {
+				case MATH:
+					state.pop(Dollar{})
+					state.mode = NORMAL
+				case NORMAL:
+					state.push(Dollar{})
+					state.mode = MATH
+				}
+			default:
+				if contains(state.rules.VerbatimDelimiters, string(token)) {
+					state.push(Other(token[0]))
+					state.mode = VERBATIM
+					state.verbatim = ""
+				}
+			}
+		case VERBATIM:
+			state.verbatim += string(token)
+			last := state.peak()
+			if strings.HasSuffix(state.verbatim, last.symbol.closing()) {
+				state.mode = NORMAL
+				state.verbatim = ""
+				state.pop(last.symbol)
+			}
+		}
+		if state.stopped {
+			break
+		}
+	}
+	if len(state.stack) != 0 && !state.stopped {
+		last := state.peak()
+		state.emit(Diagnostic{
+			Pos:      state.pos(),
+			Severity: Error,
+			Code:     "unexpected-eof",
+			Message:  fmt.Sprintf("unexpected end of file, expected %q", last.symbol.closing()),
+			Related: []Related{
+				{Pos: last.pos, Message: fmt.Sprintf("to close %q opened here", last.symbol.opening())},
+			},
+		})
+	}
+}
+
+func (state *State) pos() Position {
+	return Position{File: state.filename, Line: int(state.line), Col: state.col, Offset: state.tokenOffset}
+}
+
+// emit reports d unless MaxErrors diagnostics have already been
+// reported; diagnostics past the cap are dropped and not counted.
+func (state *State) emit(d Diagnostic) {
+	if state.maxErrors > 0 && state.errors >= state.maxErrors {
+		return
+	}
+	state.errors++
+	state.report(d)
+}
+
+// findOpener searches down from the top of the stack, within
+// maxRecoveryDepth frames, for a frame matching symbol.
+func (state *State) findOpener(symbol Symbol) (int, bool) {
+	bottom := len(state.stack) - maxRecoveryDepth
+	if bottom < 0 {
+		bottom = 0
+	}
+	for i := len(state.stack) - 1; i >= bottom; i-- {
+		if state.stack[i].symbol == symbol {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (state *State) push(symbol Symbol) {
+	state.stack = append(state.stack, LocatedSymbol{symbol, state.pos()})
+}
+
+func (state *State) pop(symbol Symbol) {
+	if len(state.stack) == 0 {
+		state.emit(Diagnostic{
+			Pos:      state.pos(),
+			Severity: Error,
+			Code:     "unexpected-closer",
+			Message:  fmt.Sprintf("unexpected %q, closed without opening", symbol.closing()),
+		})
+		if state.recover == RecoverPanic {
+			state.stopped = true
+		}
+		return
+	}
+
+	last := state.peak()
+	if symbol == last.symbol {
+		state.stack = state.stack[:len(state.stack)-1]
+		return
+	}
+
+	mismatch := Diagnostic{
+		Pos:      state.pos(),
+		Severity: Error,
+		Code:     "mismatched-closer",
+		Message:  fmt.Sprintf("unexpected %q, expected %q", symbol.closing(), last.symbol.closing()),
+		Related: []Related{
+			{Pos: last.pos, Message: fmt.Sprintf("to close %q opened here", last.symbol.opening())},
+		},
+	}
+
+	switch state.recover {
+	case RecoverSynchronize:
+		if idx, ok := state.findOpener(symbol); ok {
+			for i := len(state.stack) - 1; i > idx; i-- {
+				frame := state.stack[i]
+				state.emit(Diagnostic{
+					Pos:      state.pos(),
+					Severity: Warning,
+					Code:     "auto-closed",
+					Message:  fmt.Sprintf("auto-closing %q to recover", frame.symbol.opening()),
+					Related: []Related{
+						{Pos: frame.pos, Message: "opened here, never closed"},
+					},
+				})
+			}
+			state.stack = state.stack[:idx]
+			return
+		}
+		state.emit(Diagnostic{
+			Pos:      state.pos(),
+			Severity: Error,
+			Code:     "spurious-closer",
+			Message:  fmt.Sprintf("unexpected %q, no matching opener found nearby, ignoring it", symbol.closing()),
+		})
+	case RecoverPanic:
+		state.emit(mismatch)
+		state.stopped = true
+	default: // RecoverOff
+		state.emit(mismatch)
+	}
+}
+
+func (state *State) peak() LocatedSymbol {
+	return state.stack[len(state.stack)-1]
+}
+
+func (state *State) peakOrNil() (LocatedSymbol, bool) {
+	if len(state.stack) == 0 {
+		return LocatedSymbol{}, false
+	}
+	return state.peak(), true
+}
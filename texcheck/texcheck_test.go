@@ -0,0 +1,44 @@
+package texcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckMathDelimiters(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"dollar", "before $x + y$ after"},
+		{"double dollar", "before $$x + y$$ after"},
+		{"paren math", `before \(x + y\) after`},
+		{"bracket math", `before \[x + y\] after`},
+		{"nested display math", `$$ \left( \frac{1}{2} \right) $$`},
+		{"ensuremath", `\ensuremath{\alpha}`},
+		{"text escapes to text mode inside bracket math", `\[ x + \text{for all } x \]`},
+		{"text escapes to text mode inside display math", `$$ x + \text{for all {braces} too} $$`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			diagnostics, err := Check(strings.NewReader(c.input))
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if len(diagnostics) != 0 {
+				t.Fatalf("expected no diagnostics, got %v", diagnostics)
+			}
+		})
+	}
+}
+
+func TestCheckMismatchedDisplayMath(t *testing.T) {
+	diagnostics, err := Check(strings.NewReader("$$ a $"))
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected a diagnostic for unclosed display math, got none")
+	}
+}